@@ -0,0 +1,401 @@
+package gitkit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/ini.v1"
+	"gopkg.in/yaml.v3"
+)
+
+// Value is a single untyped config value with typed accessors. Providers
+// hand these back instead of raw strings so callers don't all re-invent
+// their own parsing.
+type Value struct {
+	raw any
+}
+
+func (v Value) String() string {
+	if v.raw == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", v.raw)
+}
+
+func (v Value) Int() (int, error) {
+	return strconv.Atoi(strings.TrimSpace(v.String()))
+}
+
+func (v Value) Bool() (bool, error) {
+	return strconv.ParseBool(strings.TrimSpace(v.String()))
+}
+
+func (v Value) Duration() (time.Duration, error) {
+	return time.ParseDuration(strings.TrimSpace(v.String()))
+}
+
+func (v Value) StringSlice() ([]string, error) {
+	switch t := v.raw.(type) {
+	case []string:
+		return t, nil
+	case []any:
+		out := make([]string, len(t))
+		for i, e := range t {
+			out[i] = fmt.Sprintf("%v", e)
+		}
+		return out, nil
+	default:
+		s := v.String()
+		if s == "" {
+			return nil, nil
+		}
+		parts := strings.Split(s, ",")
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+		return parts, nil
+	}
+}
+
+// Provider is a single source of config values, addressed by dot-path
+// key (e.g. "core.repositoryformatversion").
+type Provider interface {
+	Get(key string) (Value, error)
+	// Watch registers cb to be called whenever key's value changes.
+	// Providers that can't watch (e.g. CLI args) return ErrWatchUnsupported.
+	Watch(key string, cb func(Value)) error
+}
+
+var ErrWatchUnsupported = fmt.Errorf("provider does not support watching")
+var ErrKeyNotFound = fmt.Errorf("key not found")
+
+// Config layers providers together. Lookups walk providers from the
+// last-added to the first, so later providers override earlier ones --
+// the same precedence rule a shell uses for PATH-like overlays.
+type Config struct {
+	providers []Provider
+}
+
+// NewConfig builds a Config from providers in lowest-to-highest priority
+// order (each one added after can override the ones before it).
+func NewConfig(providers ...Provider) *Config {
+	return &Config{providers: providers}
+}
+
+func (c *Config) Get(key string) (Value, error) {
+	for i := len(c.providers) - 1; i >= 0; i-- {
+		v, err := c.providers[i].Get(key)
+		if err == nil {
+			return v, nil
+		}
+	}
+	return Value{}, fmt.Errorf("%w: %s", ErrKeyNotFound, key)
+}
+
+func (c *Config) Int(key string) (int, error) {
+	v, err := c.Get(key)
+	if err != nil {
+		return 0, err
+	}
+	return v.Int()
+}
+
+func (c *Config) Bool(key string) (bool, error) {
+	v, err := c.Get(key)
+	if err != nil {
+		return false, err
+	}
+	return v.Bool()
+}
+
+func (c *Config) Duration(key string) (time.Duration, error) {
+	v, err := c.Get(key)
+	if err != nil {
+		return 0, err
+	}
+	return v.Duration()
+}
+
+func (c *Config) StringSlice(key string) ([]string, error) {
+	v, err := c.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return v.StringSlice()
+}
+
+// Watch registers cb against every provider that supports watching key,
+// so the caller is notified regardless of which layer the value lives in.
+func (c *Config) Watch(key string, cb func(Value)) error {
+	watched := false
+	for _, p := range c.providers {
+		if err := p.Watch(key, cb); err == nil {
+			watched = true
+		} else if err != ErrWatchUnsupported {
+			return err
+		}
+	}
+	if !watched {
+		return fmt.Errorf("no provider could watch %q", key)
+	}
+	return nil
+}
+
+// splitDotPath splits "core.repositoryformatversion" into ("core",
+// "repositoryformatversion"); keys without a dot return ("", key).
+func splitDotPath(key string) (section, name string) {
+	section, name, ok := strings.Cut(key, ".")
+	if !ok {
+		return "", key
+	}
+	return section, name
+}
+
+// --- INI provider ---
+
+type IniProvider struct {
+	path string
+	file *ini.File
+}
+
+func NewIniProvider(path string) (*IniProvider, error) {
+	f, err := ini.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("loading ini config %s: %w", path, err)
+	}
+	return &IniProvider{path: path, file: f}, nil
+}
+
+func (p *IniProvider) Get(key string) (Value, error) {
+	section, name := splitDotPath(key)
+	k := p.file.Section(section).Key(name)
+	if k.String() == "" && !p.file.Section(section).HasKey(name) {
+		return Value{}, ErrKeyNotFound
+	}
+	return Value{raw: k.String()}, nil
+}
+
+func (p *IniProvider) Watch(key string, cb func(Value)) error {
+	return watchFile(p.path, func() {
+		if f, err := ini.Load(p.path); err == nil {
+			p.file = f
+			if v, err := p.Get(key); err == nil {
+				cb(v)
+			}
+		}
+	})
+}
+
+// --- YAML / JSON providers ---
+
+type mapProvider struct {
+	path   string
+	values map[string]any
+	decode func([]byte) (map[string]any, error)
+}
+
+func NewYAMLProvider(path string) (Provider, error) {
+	return newMapProvider(path, func(b []byte) (map[string]any, error) {
+		out := map[string]any{}
+		err := yaml.Unmarshal(b, &out)
+		return out, err
+	})
+}
+
+func NewJSONProvider(path string) (Provider, error) {
+	return newMapProvider(path, func(b []byte) (map[string]any, error) {
+		out := map[string]any{}
+		err := json.Unmarshal(b, &out)
+		return out, err
+	})
+}
+
+func newMapProvider(path string, decode func([]byte) (map[string]any, error)) (*mapProvider, error) {
+	p := &mapProvider{path: path, decode: decode}
+	if err := p.reload(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *mapProvider) reload() error {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", p.path, err)
+	}
+	values, err := p.decode(data)
+	if err != nil {
+		return fmt.Errorf("parsing %s: %w", p.path, err)
+	}
+	p.values = values
+	return nil
+}
+
+func (p *mapProvider) Get(key string) (Value, error) {
+	section, name := splitDotPath(key)
+	node, ok := p.values[section]
+	if !ok {
+		if v, ok := p.values[key]; ok {
+			return Value{raw: v}, nil
+		}
+		return Value{}, ErrKeyNotFound
+	}
+	nested, ok := node.(map[string]any)
+	if !ok {
+		return Value{raw: node}, nil
+	}
+	v, ok := nested[name]
+	if !ok {
+		return Value{}, ErrKeyNotFound
+	}
+	return Value{raw: v}, nil
+}
+
+func (p *mapProvider) Watch(key string, cb func(Value)) error {
+	return watchFile(p.path, func() {
+		if err := p.reload(); err == nil {
+			if v, err := p.Get(key); err == nil {
+				cb(v)
+			}
+		}
+	})
+}
+
+// --- env provider ---
+
+// EnvProvider reads "PREFIX_SECTION_NAME" for dot-path key "section.name".
+type EnvProvider struct {
+	Prefix string
+}
+
+func NewEnvProvider(prefix string) *EnvProvider {
+	return &EnvProvider{Prefix: prefix}
+}
+
+func (p *EnvProvider) envName(key string) string {
+	upper := strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+	if p.Prefix == "" {
+		return upper
+	}
+	return p.Prefix + "_" + upper
+}
+
+func (p *EnvProvider) Get(key string) (Value, error) {
+	if v, ok := os.LookupEnv(p.envName(key)); ok {
+		return Value{raw: v}, nil
+	}
+	return Value{}, ErrKeyNotFound
+}
+
+func (p *EnvProvider) Watch(key string, cb func(Value)) error {
+	return ErrWatchUnsupported
+}
+
+// --- CLI provider ---
+
+// CLIProvider reads values out of a pre-parsed "--key=value" argument
+// slice; it's layered on top as the highest-priority provider so flags
+// win over everything else.
+type CLIProvider struct {
+	values map[string]string
+}
+
+func NewCLIProvider(args []string) *CLIProvider {
+	values := map[string]string{}
+	for _, a := range args {
+		a = strings.TrimPrefix(a, "--")
+		key, val, ok := strings.Cut(a, "=")
+		if !ok {
+			val = "true"
+		}
+		values[key] = val
+	}
+	return &CLIProvider{values: values}
+}
+
+func (p *CLIProvider) Get(key string) (Value, error) {
+	if v, ok := p.values[key]; ok {
+		return Value{raw: v}, nil
+	}
+	return Value{}, ErrKeyNotFound
+}
+
+func (p *CLIProvider) Watch(key string, cb func(Value)) error {
+	return ErrWatchUnsupported
+}
+
+// --- file watching ---
+
+// fileWatcher is the single fsnotify watcher + goroutine backing every
+// watchFile call against one path, so N providers watching the same file
+// share one watcher instead of leaking one apiece.
+type fileWatcher struct {
+	watcher *fsnotify.Watcher
+
+	mu        sync.Mutex
+	callbacks []func()
+}
+
+var (
+	watchersMu sync.Mutex
+	watchers   = map[string]*fileWatcher{}
+)
+
+// watchFile starts a fsnotify watcher once per path and invokes every
+// registered callback whenever the file is written or replaced; later
+// calls for a path already being watched just add onChange to that
+// watcher's callback list.
+func watchFile(path string, onChange func()) error {
+	watchersMu.Lock()
+	defer watchersMu.Unlock()
+
+	fw, ok := watchers[path]
+	if !ok {
+		w, err := fsnotify.NewWatcher()
+		if err != nil {
+			return err
+		}
+		if err := w.Add(path); err != nil {
+			w.Close()
+			return err
+		}
+		fw = &fileWatcher{watcher: w}
+		watchers[path] = fw
+		go fw.run()
+	}
+
+	fw.mu.Lock()
+	fw.callbacks = append(fw.callbacks, onChange)
+	fw.mu.Unlock()
+	return nil
+}
+
+func (fw *fileWatcher) run() {
+	defer fw.watcher.Close()
+	for {
+		select {
+		case ev, ok := <-fw.watcher.Events:
+			if !ok {
+				return
+			}
+			if ev.Op&(fsnotify.Write|fsnotify.Create) != 0 {
+				fw.mu.Lock()
+				callbacks := append([]func(){}, fw.callbacks...)
+				fw.mu.Unlock()
+				for _, cb := range callbacks {
+					cb()
+				}
+			}
+		case _, ok := <-fw.watcher.Errors:
+			if !ok {
+				return
+			}
+		}
+	}
+}
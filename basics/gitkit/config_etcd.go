@@ -0,0 +1,61 @@
+//go:build etcd
+
+package gitkit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// EtcdProvider reads values from an etcd cluster under a key prefix and
+// can watch a key for live updates, unlike the file-based providers'
+// fsnotify watcher.
+type EtcdProvider struct {
+	client *clientv3.Client
+	prefix string
+}
+
+func NewEtcdProvider(endpoints []string, prefix string) (*EtcdProvider, error) {
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   endpoints,
+		DialTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("connecting to etcd: %w", err)
+	}
+	return &EtcdProvider{client: client, prefix: prefix}, nil
+}
+
+func (p *EtcdProvider) fullKey(key string) string {
+	return strings.TrimSuffix(p.prefix, "/") + "/" + strings.ReplaceAll(key, ".", "/")
+}
+
+func (p *EtcdProvider) Get(key string) (Value, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := p.client.Get(ctx, p.fullKey(key))
+	if err != nil {
+		return Value{}, fmt.Errorf("reading etcd key %s: %w", key, err)
+	}
+	if len(resp.Kvs) == 0 {
+		return Value{}, ErrKeyNotFound
+	}
+	return Value{raw: string(resp.Kvs[0].Value)}, nil
+}
+
+func (p *EtcdProvider) Watch(key string, cb func(Value)) error {
+	ch := p.client.Watch(context.Background(), p.fullKey(key))
+	go func() {
+		for resp := range ch {
+			for _, ev := range resp.Events {
+				cb(Value{raw: string(ev.Kv.Value)})
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,51 @@
+//go:build vault
+
+package gitkit
+
+import (
+	"fmt"
+
+	vaultapi "github.com/hashicorp/vault/api"
+)
+
+// VaultProvider reads secrets out of a Vault KV mount. Dot-path keys map
+// to "<mount>/data/<section>" with "name" as the field, mirroring how
+// the other structured providers split "section.name".
+type VaultProvider struct {
+	client *vaultapi.Client
+	mount  string
+}
+
+func NewVaultProvider(addr, token, mount string) (*VaultProvider, error) {
+	cfg := vaultapi.DefaultConfig()
+	cfg.Address = addr
+	client, err := vaultapi.NewClient(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("creating vault client: %w", err)
+	}
+	client.SetToken(token)
+	return &VaultProvider{client: client, mount: mount}, nil
+}
+
+func (p *VaultProvider) Get(key string) (Value, error) {
+	section, name := splitDotPath(key)
+	secret, err := p.client.Logical().Read(fmt.Sprintf("%s/data/%s", p.mount, section))
+	if err != nil {
+		return Value{}, fmt.Errorf("reading vault secret %s: %w", section, err)
+	}
+	if secret == nil {
+		return Value{}, ErrKeyNotFound
+	}
+	data, _ := secret.Data["data"].(map[string]any)
+	v, ok := data[name]
+	if !ok {
+		return Value{}, ErrKeyNotFound
+	}
+	return Value{raw: v}, nil
+}
+
+// Watch is unsupported: Vault KV has no native change-notification API,
+// so callers that need freshness should poll Get instead.
+func (p *VaultProvider) Watch(key string, cb func(Value)) error {
+	return ErrWatchUnsupported
+}
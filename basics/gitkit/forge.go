@@ -0,0 +1,190 @@
+package gitkit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// State is a commit status state, matching the small enum both Gitea
+// and GitHub statuses APIs accept.
+type State string
+
+const (
+	StatePending State = "pending"
+	StateSuccess State = "success"
+	StateError   State = "error"
+	StateFailure State = "failure"
+	StateWarning State = "warning"
+)
+
+// Status is what gets posted against a commit sha.
+type Status struct {
+	Context     string `json:"context"`
+	Description string `json:"description"`
+	State       State  `json:"state"`
+	TargetURL   string `json:"target_url"`
+}
+
+// StatusPublisher pushes a Status for a commit sha to a forge.
+type StatusPublisher interface {
+	Publish(ctx context.Context, owner, repo, sha string, s Status) error
+}
+
+// GiteaPublisher posts to a self-hosted (or gitea.com) instance's
+// statuses endpoint.
+type GiteaPublisher struct {
+	BaseURL string // e.g. "https://gitea.example.com"
+	Token   string
+	Client  *Client
+}
+
+func (p *GiteaPublisher) Publish(ctx context.Context, owner, repo, sha string, s Status) error {
+	url := fmt.Sprintf("%s/api/v1/repos/%s/%s/statuses/%s", strings.TrimSuffix(p.BaseURL, "/"), owner, repo, sha)
+	return postStatus(ctx, p.client(), url, "token "+p.Token, s)
+}
+
+func (p *GiteaPublisher) client() *Client {
+	if p.Client != nil {
+		return p.Client
+	}
+	return NewClient()
+}
+
+// GitHubPublisher posts to github.com's statuses endpoint.
+type GitHubPublisher struct {
+	Token  string
+	Client *Client
+}
+
+func (p *GitHubPublisher) Publish(ctx context.Context, owner, repo, sha string, s Status) error {
+	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/statuses/%s", owner, repo, sha)
+	return postStatus(ctx, p.client(), url, "Bearer "+p.Token, s)
+}
+
+func (p *GitHubPublisher) client() *Client {
+	if p.Client != nil {
+		return p.Client
+	}
+	return NewClient()
+}
+
+func postStatus(ctx context.Context, client *Client, url, authHeader string, s Status) error {
+	body, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", authHeader)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("publishing status: unexpected response %s", resp.Status)
+	}
+	return nil
+}
+
+// RemoteInfo is the {host, owner, repo} triple derived from a remote URL.
+type RemoteInfo struct {
+	Host  string
+	Owner string
+	Repo  string
+}
+
+var (
+	httpsRemoteRe = regexp.MustCompile(`^https?://([^/]+)/([^/]+)/([^/]+?)(\.git)?/?$`)
+	scpRemoteRe   = regexp.MustCompile(`^[^@]+@([^:]+):([^/]+)/([^/]+?)(\.git)?/?$`)
+	sshRemoteRe   = regexp.MustCompile(`^ssh://[^@]+@([^/]+)/([^/]+)/([^/]+?)(\.git)?/?$`)
+)
+
+// RemoteInfo parses `[remote "origin"] url = ...` out of the repo config
+// and splits it into {host, owner, repo}, understanding the three common
+// URL forms: https://host/o/r.git, git@host:o/r.git, ssh://git@host/o/r.
+func (repo *GitRepository) RemoteInfo() (RemoteInfo, error) {
+	v, err := repo.Config.Get(`remote "origin".url`)
+	if err != nil {
+		return RemoteInfo{}, fmt.Errorf("no origin remote configured: %w", err)
+	}
+	url := v.String()
+
+	for _, re := range []*regexp.Regexp{httpsRemoteRe, scpRemoteRe, sshRemoteRe} {
+		if m := re.FindStringSubmatch(url); m != nil {
+			return RemoteInfo{Host: m[1], Owner: m[2], Repo: m[3]}, nil
+		}
+	}
+	return RemoteInfo{}, fmt.Errorf("unrecognized remote url form: %q", url)
+}
+
+// publisherFor picks Gitea vs GitHub based on the remote host; anything
+// that isn't github.com is assumed to be a Gitea (or Gitea-compatible)
+// instance reachable at https://<host>.
+func publisherFor(host, token string) StatusPublisher {
+	if host == "github.com" {
+		return &GitHubPublisher{Token: token}
+	}
+	return &GiteaPublisher{BaseURL: "https://" + host, Token: token}
+}
+
+// PublishStatus resolves HEAD when sha is empty and dispatches s to
+// whichever forge repo.RemoteInfo() points at, authenticating with
+// token.
+func (repo *GitRepository) PublishStatus(ctx context.Context, sha, token string, s Status) error {
+	if sha == "" {
+		resolved, err := repo.Refs().Resolve("HEAD")
+		if err != nil {
+			return fmt.Errorf("resolving HEAD: %w", err)
+		}
+		sha = resolved
+	}
+
+	info, err := repo.RemoteInfo()
+	if err != nil {
+		return err
+	}
+
+	return publisherFor(info.Host, token).Publish(ctx, info.Owner, info.Repo, sha, s)
+}
+
+// RunGostatusCLI implements the `gostatus <state> <context> <target-url>
+// [sha]` command so local hooks and CI runners can drop status pings
+// without curl scripting.
+func RunGostatusCLI(args []string) error {
+	if len(args) < 3 {
+		return fmt.Errorf("usage: gostatus <pending|success|error|failure|warning> <context> <target-url> [sha]")
+	}
+
+	repo, err := NewGitRepository(".", false)
+	if err != nil {
+		return err
+	}
+
+	sha := ""
+	if len(args) > 3 {
+		sha = args[3]
+	}
+
+	token := os.Getenv("GOSTATUS_TOKEN")
+	status := Status{
+		State:       State(args[0]),
+		Context:     args[1],
+		TargetURL:   args[2],
+		Description: fmt.Sprintf("%s: %s", args[1], args[0]),
+	}
+
+	return repo.PublishStatus(context.Background(), sha, token, status)
+}
@@ -0,0 +1,62 @@
+package gitkit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func gitConfigRepo(t *testing.T, content string) *GitRepository {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("writing config: %v", err)
+	}
+	ini, err := NewIniProvider(path)
+	if err != nil {
+		t.Fatalf("NewIniProvider: %v", err)
+	}
+	return &GitRepository{Config: NewConfig(ini)}
+}
+
+// TestRemoteInfoParsesSubsectionHeader pins down the one thing RemoteInfo
+// depends on that the surrounding code can't verify by itself: that
+// gopkg.in/ini.v1 exposes Git's `[remote "origin"]` subsection header as
+// a section literally named `remote "origin"`, which is what
+// `repo.Config.Get("remote \"origin\".url")` looks up.
+func TestRemoteInfoParsesSubsectionHeader(t *testing.T) {
+	repo := gitConfigRepo(t, "[core]\n\trepositoryformatversion = 0\n"+
+		"[remote \"origin\"]\n\turl = https://github.com/acme/widgets.git\n"+
+		"\tfetch = +refs/heads/*:refs/remotes/origin/*\n")
+
+	info, err := repo.RemoteInfo()
+	if err != nil {
+		t.Fatalf("RemoteInfo: %v", err)
+	}
+	if info.Host != "github.com" || info.Owner != "acme" || info.Repo != "widgets" {
+		t.Fatalf("RemoteInfo = %+v, want {github.com acme widgets}", info)
+	}
+}
+
+// TestRemoteInfoMissingOrigin checks the no-origin-configured error path.
+func TestRemoteInfoMissingOrigin(t *testing.T) {
+	repo := gitConfigRepo(t, "[core]\n\trepositoryformatversion = 0\n")
+
+	if _, err := repo.RemoteInfo(); err == nil {
+		t.Fatal("expected an error when no origin remote is configured")
+	}
+}
+
+// TestRemoteInfoParsesSCPStyleURL covers the git@host:owner/repo form
+// alongside the https form already exercised above.
+func TestRemoteInfoParsesSCPStyleURL(t *testing.T) {
+	repo := gitConfigRepo(t, "[remote \"origin\"]\n\turl = git@gitea.example.com:acme/widgets.git\n")
+
+	info, err := repo.RemoteInfo()
+	if err != nil {
+		t.Fatalf("RemoteInfo: %v", err)
+	}
+	if info.Host != "gitea.example.com" || info.Owner != "acme" || info.Repo != "widgets" {
+		t.Fatalf("RemoteInfo = %+v, want {gitea.example.com acme widgets}", info)
+	}
+}
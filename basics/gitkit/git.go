@@ -0,0 +1,613 @@
+package gitkit
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type GitRepository struct {
+	Worktree string
+	GitDir   string
+	Config   *Config
+}
+
+func NewGitRepository(path string, force bool) (*GitRepository, error) {
+	repo := &GitRepository{
+		Worktree: path,
+		GitDir:   filepath.Join(path, ".git"),
+	}
+
+	if !(force || isDir(repo.GitDir)) {
+		return nil, fmt.Errorf("not a Git repository: %s", path)
+	}
+
+	// Layer an env provider (GIT_*) over the on-disk config so tests and
+	// tools can override repo settings without touching .git/config.
+	cf := filepath.Join(repo.GitDir, "config")
+	if _, err := os.Stat(cf); err == nil {
+		ini, err := NewIniProvider(cf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config: %v", err)
+		}
+		repo.Config = NewConfig(ini, NewEnvProvider("GIT"))
+	} else if !force {
+		return nil, fmt.Errorf("configuration file missing")
+	} else {
+		repo.Config = NewConfig(NewEnvProvider("GIT"))
+	}
+
+	if !force {
+		version, err := repo.Config.Int("core.repositoryformatversion")
+		if err != nil || version != 0 {
+			return nil, fmt.Errorf("unsupported repositoryformatversion: %v", err)
+		}
+	}
+
+	return repo, nil
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// --- objects ---
+
+// Object is implemented by every loose git object kind.
+type Object interface {
+	Type() string
+	Serialize() []byte
+	Deserialize([]byte)
+}
+
+// Blob is the simplest object: raw file content, stored verbatim.
+type Blob struct {
+	Data []byte
+}
+
+func (b *Blob) Type() string      { return "blob" }
+func (b *Blob) Serialize() []byte { return b.Data }
+func (b *Blob) Deserialize(d []byte) {
+	b.Data = append([]byte(nil), d...)
+}
+
+// TreeEntry is one row of a tree object: a mode, a name, and the sha of
+// the object it points to.
+type TreeEntry struct {
+	Mode string
+	Name string
+	SHA  string
+}
+
+// Tree lists the entries of a directory, each pointing at a blob or
+// another tree.
+type Tree struct {
+	Entries []TreeEntry
+}
+
+func (t *Tree) Type() string { return "tree" }
+
+// Serialize writes entries in Git's canonical order: byte-wise sorted by
+// name, with directory entries compared as if their name had a trailing
+// "/" so "foo" sorts after "foo.go" but before "foo/bar".
+func (t *Tree) Serialize() []byte {
+	sorted := append([]TreeEntry(nil), t.Entries...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return treeSortKey(sorted[i]) < treeSortKey(sorted[j])
+	})
+
+	var buf bytes.Buffer
+	for _, e := range sorted {
+		fmt.Fprintf(&buf, "%s %s\x00", canonicalMode(e.Mode), e.Name)
+		buf.Write(mustHexDecode(e.SHA))
+	}
+	return buf.Bytes()
+}
+
+// canonicalMode strips any leading zeros off a tree entry's mode, e.g.
+// "040000" -> "40000", so entries built with the Unix-style zero-padded
+// form still serialize to the exact bytes upstream Git would write.
+func canonicalMode(mode string) string {
+	trimmed := strings.TrimLeft(mode, "0")
+	if trimmed == "" {
+		return "0"
+	}
+	return trimmed
+}
+
+func treeSortKey(e TreeEntry) string {
+	if isDirMode(e.Mode) { // canonical directory mode is 40000, no leading zero
+		return e.Name + "/"
+	}
+	return e.Name
+}
+
+func isDirMode(mode string) bool {
+	return canonicalMode(mode) == "40000"
+}
+
+func (t *Tree) Deserialize(d []byte) {
+	t.Entries = nil
+	for len(d) > 0 {
+		sp := bytes.IndexByte(d, ' ')
+		mode := string(d[:sp])
+		d = d[sp+1:]
+
+		nul := bytes.IndexByte(d, 0)
+		name := string(d[:nul])
+		d = d[nul+1:]
+
+		sha := fmt.Sprintf("%x", d[:20])
+		d = d[20:]
+
+		t.Entries = append(t.Entries, TreeEntry{Mode: mode, Name: name, SHA: sha})
+	}
+}
+
+func mustHexDecode(s string) []byte {
+	b := make([]byte, 20)
+	fmt.Sscanf(s, "%x", &b)
+	return b
+}
+
+// Commit is a loosely-typed key/value header block followed by a free
+// text message, exactly like Git's on-disk format.
+type Commit struct {
+	Tree      string
+	Parents   []string // preserved in insertion order, matters for upstream sha parity
+	Author    string
+	Committer string
+	Message   string
+}
+
+func (c *Commit) Type() string { return "commit" }
+
+func (c *Commit) Serialize() []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "tree %s\n", c.Tree)
+	for _, p := range c.Parents {
+		fmt.Fprintf(&buf, "parent %s\n", p)
+	}
+	fmt.Fprintf(&buf, "author %s\n", c.Author)
+	fmt.Fprintf(&buf, "committer %s\n", c.Committer)
+	buf.WriteByte('\n')
+	buf.WriteString(c.Message)
+	return buf.Bytes()
+}
+
+func (c *Commit) Deserialize(d []byte) {
+	c.Parents = nil
+	lines := strings.Split(string(d), "\n")
+	for i, line := range lines {
+		if line == "" {
+			c.Message = strings.Join(lines[i+1:], "\n")
+			break
+		}
+		key, val, _ := strings.Cut(line, " ")
+		switch key {
+		case "tree":
+			c.Tree = val
+		case "parent":
+			c.Parents = append(c.Parents, val)
+		case "author":
+			c.Author = val
+		case "committer":
+			c.Committer = val
+		}
+	}
+}
+
+// Tag is an annotated tag object.
+type Tag struct {
+	Object  string
+	Kind    string
+	TagName string
+	Tagger  string
+	Message string
+}
+
+func (t *Tag) Type() string { return "tag" }
+
+func (t *Tag) Serialize() []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "object %s\n", t.Object)
+	fmt.Fprintf(&buf, "type %s\n", t.Kind)
+	fmt.Fprintf(&buf, "tag %s\n", t.TagName)
+	fmt.Fprintf(&buf, "tagger %s\n", t.Tagger)
+	buf.WriteByte('\n')
+	buf.WriteString(t.Message)
+	return buf.Bytes()
+}
+
+func (t *Tag) Deserialize(d []byte) {
+	lines := strings.Split(string(d), "\n")
+	for i, line := range lines {
+		if line == "" {
+			t.Message = strings.Join(lines[i+1:], "\n")
+			break
+		}
+		key, val, _ := strings.Cut(line, " ")
+		switch key {
+		case "object":
+			t.Object = val
+		case "type":
+			t.Kind = val
+		case "tag":
+			t.TagName = val
+		case "tagger":
+			t.Tagger = val
+		}
+	}
+}
+
+func newObject(kind string) (Object, error) {
+	switch kind {
+	case "blob":
+		return &Blob{}, nil
+	case "tree":
+		return &Tree{}, nil
+	case "commit":
+		return &Commit{}, nil
+	case "tag":
+		return &Tag{}, nil
+	default:
+		return nil, fmt.Errorf("unknown object type %q", kind)
+	}
+}
+
+func objectPath(gitDir, sha string) string {
+	return filepath.Join(gitDir, "objects", sha[:2], sha[2:])
+}
+
+// ReadObject loads and parses the loose object stored at .git/objects/xx/yyy...
+func (repo *GitRepository) ReadObject(sha string) (Object, error) {
+	f, err := os.Open(objectPath(repo.GitDir, sha))
+	if err != nil {
+		return nil, fmt.Errorf("object %s not found: %w", sha, err)
+	}
+	defer f.Close()
+
+	zr, err := zlib.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("corrupt object %s: %w", sha, err)
+	}
+	defer zr.Close()
+
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("corrupt object %s: %w", sha, err)
+	}
+
+	sp := bytes.IndexByte(raw, ' ')
+	nul := bytes.IndexByte(raw, 0)
+	if sp < 0 || nul < 0 {
+		return nil, fmt.Errorf("malformed object header for %s", sha)
+	}
+
+	kind := string(raw[:sp])
+	size, err := strconv.Atoi(string(raw[sp+1 : nul]))
+	if err != nil {
+		return nil, fmt.Errorf("malformed object size for %s: %w", sha, err)
+	}
+	payload := raw[nul+1:]
+	if len(payload) != size {
+		return nil, fmt.Errorf("object %s size mismatch: header says %d, got %d", sha, size, len(payload))
+	}
+
+	obj, err := newObject(kind)
+	if err != nil {
+		return nil, err
+	}
+	obj.Deserialize(payload)
+	return obj, nil
+}
+
+// WriteObject hashes o's canonical "<type> <size>\x00<payload>" form,
+// zlib-compresses it, and writes it to its sharded path. It returns the
+// computed sha so callers can chain writes (e.g. tree -> commit).
+func (repo *GitRepository) WriteObject(o Object) (string, error) {
+	return repo.WriteRawObject(o.Type(), o.Serialize())
+}
+
+// WriteRawObject writes payload under its own "<kind> <size>\x00<payload>"
+// sha, without going through an Object's Serialize/Deserialize. Callers
+// that already hold a verbatim inflated object -- e.g. unpackObjects,
+// which must preserve upstream Git's sha exactly -- use this instead of
+// WriteObject so an incomplete Deserialize can't silently drop fields
+// (like a commit's gpgsig) and recompute a different sha than the one
+// the remote advertised.
+func (repo *GitRepository) WriteRawObject(kind string, payload []byte) (string, error) {
+	header := fmt.Sprintf("%s %d\x00", kind, len(payload))
+	full := append([]byte(header), payload...)
+
+	sum := sha1.Sum(full)
+	sha := fmt.Sprintf("%x", sum)
+
+	path := objectPath(repo.GitDir, sha)
+	if _, err := os.Stat(path); err == nil {
+		return sha, nil // already present, nothing to do
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "obj-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	zw := zlib.NewWriter(tmp)
+	if _, err := zw.Write(full); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return sha, nil
+}
+
+// --- refs ---
+
+// Refs resolves branch/tag refs under .git/refs and .git/packed-refs,
+// following symbolic refs such as HEAD.
+type Refs struct {
+	gitDir string
+}
+
+func (repo *GitRepository) Refs() *Refs {
+	return &Refs{gitDir: repo.GitDir}
+}
+
+// Resolve turns a ref name (or "HEAD") into the sha it ultimately points
+// at, following any "ref: " indirection.
+func (r *Refs) Resolve(name string) (string, error) {
+	if name == "HEAD" {
+		data, err := os.ReadFile(filepath.Join(r.gitDir, "HEAD"))
+		if err != nil {
+			return "", err
+		}
+		content := strings.TrimSpace(string(data))
+		if rest, ok := strings.CutPrefix(content, "ref: "); ok {
+			return r.Resolve(rest)
+		}
+		return content, nil
+	}
+
+	path := filepath.Join(r.gitDir, name)
+	if data, err := os.ReadFile(path); err == nil {
+		content := strings.TrimSpace(string(data))
+		if rest, ok := strings.CutPrefix(content, "ref: "); ok {
+			return r.Resolve(rest)
+		}
+		return content, nil
+	}
+
+	packed, err := r.readPackedRefs()
+	if err != nil {
+		return "", err
+	}
+	if sha, ok := packed[name]; ok {
+		return sha, nil
+	}
+	return "", fmt.Errorf("unknown ref %q", name)
+}
+
+func (r *Refs) readPackedRefs() (map[string]string, error) {
+	out := map[string]string{}
+	f, err := os.Open(filepath.Join(r.gitDir, "packed-refs"))
+	if os.IsNotExist(err) {
+		return out, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "^") {
+			continue
+		}
+		sha, name, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		out[name] = sha
+	}
+	return out, scanner.Err()
+}
+
+// UpdateRef points name (e.g. "refs/heads/main") at sha, creating parent
+// directories as needed.
+func (repo *GitRepository) UpdateRef(name, sha string) error {
+	path := filepath.Join(repo.GitDir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(sha+"\n"), 0o644)
+}
+
+// --- index ---
+
+// IndexEntry is a single staged file, as stored in .git/index.
+type IndexEntry struct {
+	Mode uint32
+	SHA  string
+	Path string
+}
+
+// Index mirrors the v2 on-disk format: a 12-byte header, sorted entries
+// carrying mode/oid/flags/path, and a trailing SHA-1 checksum.
+type Index struct {
+	Entries []IndexEntry
+}
+
+func (repo *GitRepository) ReadIndex() (*Index, error) {
+	data, err := os.ReadFile(filepath.Join(repo.GitDir, "index"))
+	if os.IsNotExist(err) {
+		return &Index{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 12+20 || string(data[:4]) != "DIRC" {
+		return nil, fmt.Errorf("not a git index")
+	}
+
+	version := binary.BigEndian.Uint32(data[4:8])
+	if version != 2 {
+		return nil, fmt.Errorf("unsupported index version %d", version)
+	}
+	count := binary.BigEndian.Uint32(data[8:12])
+
+	idx := &Index{}
+	off := 12
+	for i := uint32(0); i < count; i++ {
+		entryStart := off
+		// ctime/mtime(8+8) dev/ino(4+4) mode(4) uid/gid(4+4) size(4) = 40 bytes before oid
+		mode := binary.BigEndian.Uint32(data[entryStart+24 : entryStart+28])
+		oid := data[entryStart+40 : entryStart+60]
+		flags := binary.BigEndian.Uint16(data[entryStart+60 : entryStart+62])
+		nameLen := int(flags & 0x0FFF)
+
+		nameStart := entryStart + 62
+		name := string(data[nameStart : nameStart+nameLen])
+
+		// entries are NUL-padded to a multiple of 8 bytes measured from entryStart
+		entryLen := nameStart + nameLen - entryStart
+		padded := (entryLen + 8) &^ 7
+		off = entryStart + padded
+
+		idx.Entries = append(idx.Entries, IndexEntry{
+			Mode: mode,
+			SHA:  fmt.Sprintf("%x", oid),
+			Path: name,
+		})
+	}
+
+	return idx, nil
+}
+
+// WriteIndex serializes entries (sorted by path, as Git requires) back to
+// .git/index v2, appending the trailing checksum.
+func (repo *GitRepository) WriteIndex(idx *Index) error {
+	sorted := append([]IndexEntry(nil), idx.Entries...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Path < sorted[j].Path })
+
+	var buf bytes.Buffer
+	buf.WriteString("DIRC")
+	binary.Write(&buf, binary.BigEndian, uint32(2))
+	binary.Write(&buf, binary.BigEndian, uint32(len(sorted)))
+
+	for _, e := range sorted {
+		start := buf.Len()
+		buf.Write(make([]byte, 40)) // ctime/mtime/dev/ino placeholders, mode patched in below
+		buf.Write(mustHexDecode(e.SHA))
+
+		flags := uint16(len(e.Path)) & 0x0FFF
+		binary.Write(&buf, binary.BigEndian, flags)
+		buf.WriteString(e.Path)
+
+		entryLen := buf.Len() - start
+		padded := (entryLen + 8) &^ 7
+		buf.Write(make([]byte, padded-entryLen))
+
+		// patch in the real mode now that we know the byte offset
+		modeBytes := buf.Bytes()[start+24 : start+28]
+		binary.BigEndian.PutUint32(modeBytes, e.Mode)
+	}
+
+	sum := sha1.Sum(buf.Bytes())
+	buf.Write(sum[:])
+
+	return os.WriteFile(filepath.Join(repo.GitDir, "index"), buf.Bytes(), 0o644)
+}
+
+// --- plumbing commands ---
+
+// HashObject computes (and optionally writes) the object for data as kind.
+func (repo *GitRepository) HashObject(kind string, data []byte, write bool) (string, error) {
+	obj, err := newObject(kind)
+	if err != nil {
+		return "", err
+	}
+	obj.Deserialize(data)
+	if !write {
+		payload := obj.Serialize()
+		header := fmt.Sprintf("%s %d\x00", obj.Type(), len(payload))
+		sum := sha1.Sum(append([]byte(header), payload...))
+		return fmt.Sprintf("%x", sum), nil
+	}
+	return repo.WriteObject(obj)
+}
+
+// CatFile returns the raw payload bytes of sha, as `git cat-file -p` would.
+func (repo *GitRepository) CatFile(sha string) ([]byte, error) {
+	obj, err := repo.ReadObject(sha)
+	if err != nil {
+		return nil, err
+	}
+	return obj.Serialize(), nil
+}
+
+// WriteTree builds and writes a Tree object from entries.
+func (repo *GitRepository) WriteTree(entries []TreeEntry) (string, error) {
+	return repo.WriteObject(&Tree{Entries: entries})
+}
+
+// CommitTree writes a Commit object pointing at tree with the given
+// parents (in insertion order) and message.
+func (repo *GitRepository) CommitTree(tree string, parents []string, author, message string) (string, error) {
+	c := &Commit{
+		Tree:      tree,
+		Parents:   parents,
+		Author:    author,
+		Committer: author,
+		Message:   message,
+	}
+	return repo.WriteObject(c)
+}
+
+// Log walks first-parent history starting at sha, returning commits
+// oldest-last (i.e. in the order `git log` prints them).
+func (repo *GitRepository) Log(sha string) ([]*Commit, error) {
+	var out []*Commit
+	for sha != "" {
+		obj, err := repo.ReadObject(sha)
+		if err != nil {
+			return out, err
+		}
+		c, ok := obj.(*Commit)
+		if !ok {
+			return out, fmt.Errorf("%s is not a commit", sha)
+		}
+		out = append(out, c)
+		if len(c.Parents) == 0 {
+			break
+		}
+		sha = c.Parents[0]
+	}
+	return out, nil
+}
@@ -0,0 +1,99 @@
+package gitkit
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestWriteReadObjectRoundTrip covers the loose-object store end to end:
+// write a blob, a tree pointing at it, and a commit pointing at the
+// tree, then read each back and check it deserializes to the same
+// content.
+func TestWriteReadObjectRoundTrip(t *testing.T) {
+	repo := &GitRepository{GitDir: t.TempDir()}
+
+	blobSHA, err := repo.WriteObject(&Blob{Data: []byte("hello world\n")})
+	if err != nil {
+		t.Fatalf("writing blob: %v", err)
+	}
+
+	tree := &Tree{Entries: []TreeEntry{{Mode: "100644", Name: "hello.txt", SHA: blobSHA}}}
+	treeSHA, err := repo.WriteObject(tree)
+	if err != nil {
+		t.Fatalf("writing tree: %v", err)
+	}
+
+	commit := &Commit{
+		Tree:      treeSHA,
+		Author:    "Test Author <test@example.com> 0 +0000",
+		Committer: "Test Author <test@example.com> 0 +0000",
+		Message:   "initial commit\n",
+	}
+	commitSHA, err := repo.WriteObject(commit)
+	if err != nil {
+		t.Fatalf("writing commit: %v", err)
+	}
+
+	blobObj, err := repo.ReadObject(blobSHA)
+	if err != nil {
+		t.Fatalf("reading blob: %v", err)
+	}
+	if got := string(blobObj.Serialize()); got != "hello world\n" {
+		t.Fatalf("blob content = %q, want %q", got, "hello world\n")
+	}
+
+	treeObj, err := repo.ReadObject(treeSHA)
+	if err != nil {
+		t.Fatalf("reading tree: %v", err)
+	}
+	gotTree := treeObj.(*Tree)
+	if len(gotTree.Entries) != 1 || gotTree.Entries[0].SHA != blobSHA || gotTree.Entries[0].Name != "hello.txt" {
+		t.Fatalf("tree entries = %+v, want one entry pointing at %s", gotTree.Entries, blobSHA)
+	}
+
+	commitObj, err := repo.ReadObject(commitSHA)
+	if err != nil {
+		t.Fatalf("reading commit: %v", err)
+	}
+	gotCommit := commitObj.(*Commit)
+	if gotCommit.Tree != treeSHA {
+		t.Fatalf("commit tree = %s, want %s", gotCommit.Tree, treeSHA)
+	}
+	if gotCommit.Message != "initial commit\n" {
+		t.Fatalf("commit message = %q, want %q", gotCommit.Message, "initial commit\n")
+	}
+}
+
+// TestTreeSerializeSortsDirectoriesAsIfSlashTerminated pins down Git's
+// quirky tree ordering: "foo" sorts after "foo.go" but before "foo/bar",
+// because directory names compare as if they had a trailing "/". It
+// uses the canonical "40000" directory mode (no leading zero), since
+// that's the form Git itself emits and the one real trees contain.
+func TestTreeSerializeSortsDirectoriesAsIfSlashTerminated(t *testing.T) {
+	tree := &Tree{Entries: []TreeEntry{
+		{Mode: "40000", Name: "foo", SHA: "0000000000000000000000000000000000000001"},
+		{Mode: "100644", Name: "foo.go", SHA: "0000000000000000000000000000000000000002"},
+	}}
+
+	var decoded Tree
+	decoded.Deserialize(tree.Serialize())
+
+	if len(decoded.Entries) != 2 || decoded.Entries[0].Name != "foo.go" || decoded.Entries[1].Name != "foo" {
+		t.Fatalf("entries in wrong order: %+v, want foo.go before foo", decoded.Entries)
+	}
+}
+
+// TestTreeSerializeEmitsCanonicalDirectoryMode checks that an entry
+// built with the Unix-style zero-padded mode ("040000") still
+// serializes to the exact bytes upstream Git would write ("40000"),
+// so trees built by this package hash identically to real Git trees.
+func TestTreeSerializeEmitsCanonicalDirectoryMode(t *testing.T) {
+	tree := &Tree{Entries: []TreeEntry{
+		{Mode: "040000", Name: "foo", SHA: "0000000000000000000000000000000000000001"},
+	}}
+
+	got := tree.Serialize()
+	if !bytes.HasPrefix(got, []byte("40000 foo\x00")) {
+		t.Fatalf("serialized entry = %q, want mode trimmed to canonical \"40000\"", got)
+	}
+}
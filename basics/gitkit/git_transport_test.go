@@ -0,0 +1,115 @@
+package gitkit
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"testing"
+)
+
+// packObjHeaderBytes encodes a (type, size) pair using the same
+// variable-length scheme readPackObjHeader decodes, so tests can build
+// packfiles without duplicating the production encoder.
+func packObjHeaderBytes(kind, size int) []byte {
+	first := byte(kind<<4) | byte(size&0x0f)
+	size >>= 4
+
+	var out []byte
+	for size > 0 {
+		out = append(out, first|0x80)
+		first = byte(size & 0x7f)
+		size >>= 7
+	}
+	out = append(out, first)
+	return out
+}
+
+func zlibCompress(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatalf("compressing: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("closing zlib writer: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// TestUnpackObjectsMultipleEntries guards against the countingReader
+// under-counting bug: if zlibInflate over-reports how many compressed
+// bytes it consumed, the second (and every later) object's header is
+// read from the wrong offset and the pack parse fails outright.
+func TestUnpackObjectsMultipleEntries(t *testing.T) {
+	repo := &GitRepository{GitDir: t.TempDir()}
+
+	first := []byte("hello from the first blob\n")
+	second := []byte("and the second one, which must still be reachable\n")
+
+	var pack bytes.Buffer
+	pack.WriteString("PACK")
+	binary.Write(&pack, binary.BigEndian, uint32(2))
+	binary.Write(&pack, binary.BigEndian, uint32(2))
+
+	for _, content := range [][]byte{first, second} {
+		pack.Write(packObjHeaderBytes(objBlob, len(content)))
+		pack.Write(zlibCompress(t, content))
+	}
+
+	if err := repo.unpackObjects(pack.Bytes()); err != nil {
+		t.Fatalf("unpackObjects: %v", err)
+	}
+
+	for _, content := range [][]byte{first, second} {
+		blob := &Blob{Data: content}
+		sha, err := repo.WriteObject(blob)
+		if err != nil {
+			t.Fatalf("computing sha for %q: %v", content, err)
+		}
+		obj, err := repo.ReadObject(sha)
+		if err != nil {
+			t.Fatalf("object %s not written by unpackObjects: %v", sha, err)
+		}
+		if !bytes.Equal(obj.Serialize(), content) {
+			t.Fatalf("object %s round-tripped to %q, want %q", sha, obj.Serialize(), content)
+		}
+	}
+}
+
+// TestUnpackObjectsPreservesUnknownCommitHeaders guards against
+// unpackObjects recomputing a commit's sha by round-tripping it through
+// Commit.Deserialize/Serialize: our Commit type only understands
+// tree/parent/author/committer, so a real-world commit carrying a
+// gpgsig (or any other header our type doesn't model) would silently
+// lose it and land under a different sha than upstream advertised.
+func TestUnpackObjectsPreservesUnknownCommitHeaders(t *testing.T) {
+	repo := &GitRepository{GitDir: t.TempDir()}
+
+	raw := []byte("tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n" +
+		"author A U Thor <a@example.com> 0 +0000\n" +
+		"committer A U Thor <a@example.com> 0 +0000\n" +
+		"gpgsig -----BEGIN PGP SIGNATURE-----\n not a real signature\n -----END PGP SIGNATURE-----\n" +
+		"\n" +
+		"a signed commit\n")
+
+	var pack bytes.Buffer
+	pack.WriteString("PACK")
+	binary.Write(&pack, binary.BigEndian, uint32(2))
+	binary.Write(&pack, binary.BigEndian, uint32(1))
+	pack.Write(packObjHeaderBytes(objCommit, len(raw)))
+	pack.Write(zlibCompress(t, raw))
+
+	if err := repo.unpackObjects(pack.Bytes()); err != nil {
+		t.Fatalf("unpackObjects: %v", err)
+	}
+
+	header := fmt.Sprintf("commit %d\x00", len(raw))
+	wantSHA := fmt.Sprintf("%x", sha1.Sum(append([]byte(header), raw...)))
+
+	if _, err := repo.ReadObject(wantSHA); err != nil {
+		t.Fatalf("commit not written under its upstream sha %s (gpgsig was likely dropped): %v", wantSHA, err)
+	}
+}
@@ -0,0 +1,192 @@
+package gitkit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AuthProvider attaches credentials to an outgoing request.
+type AuthProvider interface {
+	Apply(req *http.Request)
+}
+
+type BearerAuth struct{ Token string }
+
+func (a BearerAuth) Apply(req *http.Request) {
+	req.Header.Set("Authorization", "Bearer "+a.Token)
+}
+
+type BasicAuth struct{ Username, Password string }
+
+func (a BasicAuth) Apply(req *http.Request) {
+	req.SetBasicAuth(a.Username, a.Password)
+}
+
+// TokenFileAuth reads a bearer token fresh off disk on every request, so
+// a rotated token file is picked up without restarting the client.
+type TokenFileAuth struct{ Path string }
+
+func (a TokenFileAuth) Apply(req *http.Request) {
+	data, err := os.ReadFile(a.Path)
+	if err != nil {
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(data)))
+}
+
+// Client wraps http.Client with retry-with-backoff, pluggable auth, and
+// request/response logging, so every REST call in this module goes
+// through one place instead of bare http.Get calls.
+type Client struct {
+	HTTP        *http.Client
+	Auth        AuthProvider
+	MaxRetries  int
+	BaseBackoff time.Duration
+	Logger      *log.Logger
+}
+
+func NewClient() *Client {
+	return &Client{
+		HTTP:        http.DefaultClient,
+		MaxRetries:  3,
+		BaseBackoff: 200 * time.Millisecond,
+		Logger:      log.Default(),
+	}
+}
+
+// Do sends req, retrying on 5xx/429 with exponential backoff and jitter.
+// A Retry-After response header, if present, overrides the computed
+// delay. Requests with a body must set req.GetBody (as
+// http.NewRequest(WithContext) does for []byte/bytes.Reader/strings.Reader
+// bodies) so each retry attempt gets a fresh, unread copy instead of
+// resending whatever the previous attempt already drained.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	if c.Auth != nil {
+		c.Auth.Apply(req)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= c.MaxRetries; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("rewinding request body for retry: %w", err)
+			}
+			req.Body = body
+		}
+
+		c.Logger.Printf("%s %s (attempt %d)", req.Method, req.URL, attempt+1)
+
+		resp, err := c.HTTP.Do(req)
+		if err != nil {
+			lastErr = err
+		} else if resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			return resp, nil
+		} else {
+			lastErr = fmt.Errorf("request failed with status %s", resp.Status)
+			delay := retryDelay(resp, attempt, c.BaseBackoff)
+			resp.Body.Close()
+			if attempt < c.MaxRetries {
+				time.Sleep(delay)
+				continue
+			}
+			return nil, lastErr
+		}
+
+		if attempt < c.MaxRetries {
+			time.Sleep(retryDelay(nil, attempt, c.BaseBackoff))
+		}
+	}
+	return nil, lastErr
+}
+
+// retryDelay computes exponential backoff with jitter, honoring
+// Retry-After when the server sends one.
+func retryDelay(resp *http.Response, attempt int, base time.Duration) time.Duration {
+	if resp != nil {
+		if ra := resp.Header.Get("Retry-After"); ra != "" {
+			if secs, err := strconv.Atoi(ra); err == nil {
+				return time.Duration(secs) * time.Second
+			}
+		}
+	}
+	backoff := base * time.Duration(1<<attempt)
+	if base <= 0 {
+		return backoff
+	}
+	jitter := time.Duration(rand.Int63n(int64(base)))
+	return backoff + jitter
+}
+
+func (c *Client) Get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+func (c *Client) Post(ctx context.Context, url, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return c.Do(req)
+}
+
+// JSON decodes resp's body straight off the wire, without buffering the
+// whole response first.
+func (c *Client) JSON(resp *http.Response, v any) error {
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+var linkHeaderRe = regexp.MustCompile(`<([^>]+)>;\s*rel="([^"]+)"`)
+
+// nextLink extracts the rel="next" URL from an RFC 5988 Link header, if
+// present.
+func nextLink(header string) string {
+	for _, match := range linkHeaderRe.FindAllStringSubmatch(header, -1) {
+		if match[2] == "next" {
+			return match[1]
+		}
+	}
+	return ""
+}
+
+// Paginate follows rel="next" Link headers starting from req, streaming
+// each page to fn so callers never have to buffer the whole result set
+// in memory.
+func (c *Client) Paginate(ctx context.Context, req *http.Request, fn func(page *http.Response) error) error {
+	for req != nil {
+		resp, err := c.Do(req)
+		if err != nil {
+			return err
+		}
+
+		next := nextLink(resp.Header.Get("Link"))
+		if err := fn(resp); err != nil {
+			return err
+		}
+
+		if next == "" {
+			return nil
+		}
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, next, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
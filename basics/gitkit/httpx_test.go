@@ -0,0 +1,100 @@
+package gitkit
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func testClient(maxRetries int) *Client {
+	return &Client{
+		HTTP:        http.DefaultClient,
+		MaxRetries:  maxRetries,
+		BaseBackoff: 0,
+		Logger:      log.New(io.Discard, "", 0),
+	}
+}
+
+// TestDoRetriesPostBody checks that a POST retried after a 429 sends the
+// original body again instead of an empty one: the previous attempt's
+// body is always fully drained by net/http before Do loops around.
+func TestDoRetriesPostBody(t *testing.T) {
+	var bodies []string
+	attempt := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		attempt++
+		if attempt == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	client := testClient(1)
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, srv.URL, bytes.NewReader([]byte("payload")))
+	if err != nil {
+		t.Fatalf("building request: %v", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	resp.Body.Close()
+
+	if len(bodies) != 2 {
+		t.Fatalf("got %d attempts, want 2", len(bodies))
+	}
+	for i, b := range bodies {
+		if b != "payload" {
+			t.Fatalf("attempt %d body = %q, want %q", i+1, b, "payload")
+		}
+	}
+}
+
+// TestPaginateFollowsNextLink checks that Paginate keeps following
+// rel="next" Link headers and stops once a page omits one.
+func TestPaginateFollowsNextLink(t *testing.T) {
+	var pagesSeen []string
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/page1":
+			w.Header().Set("Link", `<`+srv.URL+`/page2>; rel="next"`)
+			io.WriteString(w, "one")
+		case "/page2":
+			io.WriteString(w, "two")
+		}
+	}))
+	defer srv.Close()
+
+	client := testClient(0)
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, srv.URL+"/page1", nil)
+	if err != nil {
+		t.Fatalf("building request: %v", err)
+	}
+
+	err = client.Paginate(context.Background(), req, func(page *http.Response) error {
+		defer page.Body.Close()
+		body, err := io.ReadAll(page.Body)
+		if err != nil {
+			return err
+		}
+		pagesSeen = append(pagesSeen, string(body))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Paginate: %v", err)
+	}
+
+	if len(pagesSeen) != 2 || pagesSeen[0] != "one" || pagesSeen[1] != "two" {
+		t.Fatalf("pages seen = %v, want [one two]", pagesSeen)
+	}
+}
@@ -0,0 +1,92 @@
+package gitkit
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// This file is a small, dependency-free pkt-line codec. It only knows
+// about the wire format (4-hex-digit length prefix + payload, with
+// "0000" meaning flush) so it can back any pkt-line based transport --
+// today that's the smart-HTTP client in git_transport.go, tomorrow maybe
+// a git:// or ssh transport.
+
+const pktFlush = "0000"
+
+// writePktLine writes one pkt-line frame: a 4-hex-digit length (counting
+// itself) followed by data.
+func writePktLine(w io.Writer, data []byte) error {
+	n := len(data) + 4
+	_, err := fmt.Fprintf(w, "%04x%s", n, data)
+	return err
+}
+
+// writeFlushPkt writes the special "0000" flush packet used to terminate
+// a section of a pkt-line stream.
+func writeFlushPkt(w io.Writer) error {
+	_, err := io.WriteString(w, pktFlush)
+	return err
+}
+
+// readPktLine reads one pkt-line frame. A flush packet is reported as a
+// nil slice with no error; io.EOF is returned once the stream is
+// exhausted.
+func readPktLine(r *bufio.Reader) ([]byte, error) {
+	var lenHex [4]byte
+	if _, err := io.ReadFull(r, lenHex[:]); err != nil {
+		return nil, err
+	}
+
+	var n int
+	if _, err := fmt.Sscanf(string(lenHex[:]), "%04x", &n); err != nil {
+		return nil, fmt.Errorf("bad pkt-line length %q: %w", lenHex, err)
+	}
+	if n == 0 {
+		return nil, nil // flush
+	}
+	if n < 4 {
+		return nil, fmt.Errorf("invalid pkt-line length %d", n)
+	}
+
+	payload := make([]byte, n-4)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// sideBandDemux splits a side-band-64k stream into packfile bytes (band
+// 1), progress text (band 2, forwarded to progress), and errors (band 3,
+// returned as an error). Any other band value is ignored.
+func sideBandDemux(r *bufio.Reader, pack io.Writer, progress io.Writer) error {
+	for {
+		line, err := readPktLine(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if line == nil { // flush
+			return nil
+		}
+		if len(line) == 0 {
+			continue
+		}
+
+		band, payload := line[0], line[1:]
+		switch band {
+		case 1:
+			if _, err := pack.Write(payload); err != nil {
+				return err
+			}
+		case 2:
+			if progress != nil {
+				progress.Write(payload)
+			}
+		case 3:
+			return fmt.Errorf("remote error: %s", payload)
+		}
+	}
+}
@@ -1,18 +1,23 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"net/http"
+
+	"github.com/kashish00208/go-learnings/basics/gitkit"
 )
 
 func httpFunc() {
 	fmt.Println("Http server in golang")
 
-	resp, err := http.Get("http://example.com/form")
+	client := gitkit.NewClient()
 
+	resp, err := client.Get(context.Background(), "http://example.com/form")
 	if err != nil {
-		println(err)
+		fmt.Println(err)
+		return
 	}
-	fmt.Println(resp)
+	defer resp.Body.Close()
 
+	fmt.Println(resp)
 }
@@ -2,13 +2,27 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"time"
+
+	"github.com/kashish00208/go-learnings/basics/gitkit"
 )
 
 // Constant declaration
 const s string = "Constant"
 
 func main() {
+	// `go run ./basics gostatus <state> <context> <target-url> [sha]`
+	// dispatches straight to the gostatus CLI instead of the example
+	// walkthrough below.
+	if len(os.Args) > 1 && os.Args[1] == "gostatus" {
+		if err := gitkit.RunGostatusCLI(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	fmt.Println("Hello, World!")
 	fmt.Println("Constant value:", s)
 
@@ -1,12 +1,14 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"sync"
 
 	"github.com/gorilla/websocket"
-)s
+)
 
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
@@ -14,33 +16,328 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
-func wsHandler(w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
-	if err != nil {
-		log.Println("Upgrade failed:", err)
-		return
+// Envelope is the wire format for every frame going in or out of a socket.
+//
+// Incoming frames carry Action+Params+ReqID, outgoing frames carry
+// ReqID+Code+Msg+Data. We reuse the same struct both ways so handlers
+// don't need to juggle two types.
+type Envelope struct {
+	Action string          `json:"action,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	ReqID  string          `json:"reqId,omitempty"`
+	Code   int             `json:"code,omitempty"`
+	Msg    string          `json:"msg,omitempty"`
+	Data   any             `json:"data,omitempty"`
+}
+
+// Context is handed to every handler and carries the state for a single
+// incoming request.
+type Context struct {
+	Action string
+	Params json.RawMessage
+	ReqID  string
+
+	conn *Conn
+	hub  *Hub
+
+	// set by middleware (e.g. auth) and readable by downstream handlers
+	values map[string]any
+}
+
+func (c *Context) Set(key string, v any) {
+	if c.values == nil {
+		c.values = map[string]any{}
+	}
+	c.values[key] = v
+}
+
+func (c *Context) Get(key string) (any, bool) {
+	v, ok := c.values[key]
+	return v, ok
+}
+
+// Join adds the connection handling this request to a room so it starts
+// receiving broadcasts sent to that room.
+func (c *Context) Join(room string) {
+	c.hub.join(room, c.conn)
+}
+
+// Leave removes the connection from a room.
+func (c *Context) Leave(room string) {
+	c.hub.leave(room, c.conn)
+}
+
+// HandlerFunc handles a single decoded action.
+type HandlerFunc func(*Context) (any, error)
+
+// Middleware wraps a HandlerFunc with extra behavior (auth, logging, recover...).
+type Middleware func(HandlerFunc) HandlerFunc
+
+// Router dispatches incoming envelopes to registered action handlers.
+type Router struct {
+	handlers   map[string]HandlerFunc
+	middleware []Middleware
+}
+
+func NewRouter() *Router {
+	return &Router{handlers: map[string]HandlerFunc{}}
+}
+
+func (r *Router) Use(mw ...Middleware) {
+	r.middleware = append(r.middleware, mw...)
+}
+
+func (r *Router) Handle(action string, fn HandlerFunc) {
+	r.handlers[action] = fn
+}
+
+func (r *Router) dispatch(ctx *Context) (any, error) {
+	fn, ok := r.handlers[ctx.Action]
+	if !ok {
+		return nil, fmt.Errorf("unknown action %q", ctx.Action)
+	}
+	// apply middleware innermost-last so the first registered wraps the rest
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		fn = r.middleware[i](fn)
+	}
+	return fn(ctx)
+}
+
+// --- middleware ---
+
+func loggingMiddleware(next HandlerFunc) HandlerFunc {
+	return func(ctx *Context) (any, error) {
+		log.Printf("action=%s reqId=%s", ctx.Action, ctx.ReqID)
+		return next(ctx)
+	}
+}
+
+func recoverMiddleware(next HandlerFunc) HandlerFunc {
+	return func(ctx *Context) (data any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("handler panic: %v", r)
+			}
+		}()
+		return next(ctx)
+	}
+}
+
+func authMiddleware(next HandlerFunc) HandlerFunc {
+	return func(ctx *Context) (any, error) {
+		// placeholder: real auth would inspect ctx.Params/conn headers
+		ctx.Set("authenticated", true)
+		return next(ctx)
+	}
+}
+
+// --- connection ---
+
+const sendBuffer = 32
+
+// Conn wraps a single websocket connection. Writes go through a bounded
+// channel drained by a dedicated writer goroutine so a slow client can't
+// block the hub or other connections.
+type Conn struct {
+	ws   *websocket.Conn
+	hub  *Hub
+	send chan Envelope
+
+	mu    sync.Mutex
+	rooms map[string]bool
+}
+
+func newConn(ws *websocket.Conn, hub *Hub) *Conn {
+	return &Conn{
+		ws:    ws,
+		hub:   hub,
+		send:  make(chan Envelope, sendBuffer),
+		rooms: map[string]bool{},
+	}
+}
+
+func (c *Conn) writeLoop() {
+	for env := range c.send {
+		if err := c.ws.WriteJSON(env); err != nil {
+			log.Println("write error:", err)
+			return
+		}
+	}
+}
+
+// trySend enqueues a frame, dropping it if the connection is too far
+// behind rather than stalling the broadcaster.
+func (c *Conn) trySend(env Envelope) {
+	select {
+	case c.send <- env:
+	default:
+		log.Println("dropping frame, slow consumer")
 	}
-	defer conn.Close()
+}
+
+func (c *Conn) readLoop(router *Router) {
+	defer func() {
+		c.hub.unregister(c)
+		close(c.send)
+		c.ws.Close()
+	}()
 
 	for {
-		msgType, msg, err := conn.ReadMessage()
+		var in Envelope
+		if err := c.ws.ReadJSON(&in); err != nil {
+			log.Println("read error:", err)
+			return
+		}
+
+		ctx := &Context{
+			Action: in.Action,
+			Params: in.Params,
+			ReqID:  in.ReqID,
+			conn:   c,
+			hub:    c.hub,
+		}
+
+		data, err := router.dispatch(ctx)
+		resp := Envelope{ReqID: ctx.ReqID}
 		if err != nil {
-			log.Println("Read error:", err)
-			break
+			resp.Code = 1
+			resp.Msg = err.Error()
+		} else {
+			resp.Code = 0
+			resp.Data = data
 		}
+		c.trySend(resp)
+	}
+}
+
+// --- hub / rooms ---
 
-		fmt.Printf("Received: %s\n", msg)
+// Hub owns all room membership and serializes register/unregister/broadcast
+// through a single goroutine fed by channels, so the hot broadcast path
+// never needs a lock.
+type Hub struct {
+	unregisterC chan *Conn
+	joinC       chan roomOp
+	leaveC      chan roomOp
+	broadcastC  chan broadcastOp
 
-		err = conn.WriteMessage(msgType, msg)
+	rooms map[string]map[*Conn]bool
+}
+
+type roomOp struct {
+	room string
+	conn *Conn
+}
+
+type broadcastOp struct {
+	room    string
+	payload any
+}
+
+func NewHub() *Hub {
+	h := &Hub{
+		unregisterC: make(chan *Conn),
+		joinC:       make(chan roomOp),
+		leaveC:      make(chan roomOp),
+		broadcastC:  make(chan broadcastOp, 64),
+		rooms:       map[string]map[*Conn]bool{},
+	}
+	go h.run()
+	return h
+}
+
+func (h *Hub) run() {
+	for {
+		select {
+		case c := <-h.unregisterC:
+			for room, members := range h.rooms {
+				delete(members, c)
+				if len(members) == 0 {
+					delete(h.rooms, room)
+				}
+			}
+		case op := <-h.joinC:
+			if h.rooms[op.room] == nil {
+				h.rooms[op.room] = map[*Conn]bool{}
+			}
+			h.rooms[op.room][op.conn] = true
+		case op := <-h.leaveC:
+			if members, ok := h.rooms[op.room]; ok {
+				delete(members, op.conn)
+			}
+		case op := <-h.broadcastC:
+			env := Envelope{Action: "broadcast", Data: op.payload}
+			for c := range h.rooms[op.room] {
+				c.trySend(env)
+			}
+		}
+	}
+}
+
+func (h *Hub) join(room string, c *Conn)  { h.joinC <- roomOp{room, c} }
+func (h *Hub) leave(room string, c *Conn) { h.leaveC <- roomOp{room, c} }
+func (h *Hub) unregister(c *Conn)         { h.unregisterC <- c }
+
+// Broadcast fans a payload out to every connection currently in room.
+func (h *Hub) Broadcast(room string, payload any) {
+	h.broadcastC <- broadcastOp{room: room, payload: payload}
+}
+
+// --- wiring ---
+
+func newWSRouter() *Router {
+	r := NewRouter()
+	r.Use(recoverMiddleware, loggingMiddleware, authMiddleware)
+
+	r.Handle("ping", func(ctx *Context) (any, error) {
+		return "pong", nil
+	})
+
+	r.Handle("join", func(ctx *Context) (any, error) {
+		var p struct {
+			Room string `json:"room"`
+		}
+		if err := json.Unmarshal(ctx.Params, &p); err != nil {
+			return nil, fmt.Errorf("bad params: %w", err)
+		}
+		ctx.Join(p.Room)
+		return fmt.Sprintf("joined %s", p.Room), nil
+	})
+
+	r.Handle("broadcast", func(ctx *Context) (any, error) {
+		var p struct {
+			Room    string `json:"room"`
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(ctx.Params, &p); err != nil {
+			return nil, fmt.Errorf("bad params: %w", err)
+		}
+		ctx.hub.Broadcast(p.Room, p.Message)
+		return "ok", nil
+	})
+
+	return r
+}
+
+func wsHandlerFor(hub *Hub, router *Router) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wsConn, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
-			log.Println("Write error:", err)
-			break
+			log.Println("Upgrade failed:", err)
+			return
 		}
+
+		c := newConn(wsConn, hub)
+		go c.writeLoop()
+		c.readLoop(router)
 	}
 }
 
 func main() {
-	http.HandleFunc("/ws", wsHandler)
+	hub := NewHub()
+	router := newWSRouter()
+
+	http.HandleFunc("/ws", wsHandlerFor(hub, router))
 	log.Println("WebSocket server running on :8080")
 	log.Fatal(http.ListenAndServe(":8080", nil))
 }